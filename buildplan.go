@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+// ManifestTile is one tile's placement and export metadata, as written to
+// manifest.json/.csv so builders can find each .paint file's spot on the
+// wall without opening it. PxX/PxY is the tile's true pixel offset in the
+// source image (Tile.SrcX/SrcY); it only equals GridCol*16/GridRow*16
+// when -margin and -spacing are both zero.
+type ManifestTile struct {
+	FileBase string `json:"file_base"`
+	CT       byte   `json:"ct"`
+	PxX      int    `json:"px_x"`
+	PxY      int    `json:"px_y"`
+	GridRow  int    `json:"grid_row"`
+	GridCol  int    `json:"grid_col"`
+	UnitsW   int    `json:"units_w"`
+	UnitsH   int    `json:"units_h"`
+	Name     string `json:"name"`
+}
+
+// writeBuildPlan emits manifest.json, manifest.csv, and an ASCII diagram
+// of the tiling grid into cfg.OutDir, so a builder can place frames
+// row-by-row without opening each .paint file. names maps each tile's
+// FileBase to the Name UUID assigned during export.
+func writeBuildPlan(cfg Config, plan []Tile, names map[string]string) error {
+	tiles := make([]ManifestTile, len(plan))
+	for i, t := range plan {
+		tiles[i] = ManifestTile{
+			FileBase: t.FileBase,
+			CT:       t.CT,
+			PxX:      t.SrcX,
+			PxY:      t.SrcY,
+			GridRow:  t.GridRow,
+			GridCol:  t.GridCol,
+			UnitsW:   t.UnitsW,
+			UnitsH:   t.UnitsH,
+			Name:     names[t.FileBase],
+		}
+	}
+	sort.Slice(tiles, func(i, j int) bool {
+		if tiles[i].GridRow != tiles[j].GridRow {
+			return tiles[i].GridRow < tiles[j].GridRow
+		}
+		return tiles[i].GridCol < tiles[j].GridCol
+	})
+
+	if err := writeManifestJSON(cfg.OutDir, tiles); err != nil {
+		return err
+	}
+	if err := writeManifestCSV(cfg.OutDir, tiles); err != nil {
+		return err
+	}
+	return writeManifestDiagram(cfg.OutDir, tiles)
+}
+
+// writeManifestJSON writes the manifest as indented JSON.
+func writeManifestJSON(outDir string, tiles []ManifestTile) error {
+	data, err := json.MarshalIndent(tiles, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding manifest.json: %w", err)
+	}
+	path := filepath.Join(outDir, "manifest.json")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("writing %q: %w", path, err)
+	}
+	return nil
+}
+
+// writeManifestCSV writes the manifest as a CSV, one row per tile.
+func writeManifestCSV(outDir string, tiles []ManifestTile) error {
+	path := filepath.Join(outDir, "manifest.csv")
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("creating %q: %w", path, err)
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	header := []string{"file_base", "ct", "px_x", "px_y", "grid_row", "grid_col", "units_w", "units_h", "name"}
+	if err := w.Write(header); err != nil {
+		return fmt.Errorf("writing %q: %w", path, err)
+	}
+	for _, t := range tiles {
+		row := []string{
+			t.FileBase,
+			strconv.Itoa(int(t.CT)),
+			strconv.Itoa(t.PxX),
+			strconv.Itoa(t.PxY),
+			strconv.Itoa(t.GridRow),
+			strconv.Itoa(t.GridCol),
+			strconv.Itoa(t.UnitsW),
+			strconv.Itoa(t.UnitsH),
+			t.Name,
+		}
+		if err := w.Write(row); err != nil {
+			return fmt.Errorf("writing %q: %w", path, err)
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// writeManifestDiagram writes an ASCII diagram of the tiling grid to
+// manifest.txt: one character cell per 16px unit, labelled with the
+// occupying tile's CT digit and boxed with '+'/'-'/'|' along each
+// canvas's boundary, so a builder can read the layout row-by-row without
+// opening a single .paint file.
+func writeManifestDiagram(outDir string, tiles []ManifestTile) error {
+	rows, cols := 0, 0
+	for _, t := range tiles {
+		if r := t.GridRow + t.UnitsH; r > rows {
+			rows = r
+		}
+		if c := t.GridCol + t.UnitsW; c > cols {
+			cols = c
+		}
+	}
+
+	// Each 16px unit becomes a 4-wide, 2-tall block of characters, wide
+	// enough to draw a labelled box around canvases up to 2 units across.
+	const cellW, cellH = 4, 2
+	grid := make([][]byte, rows*cellH)
+	for i := range grid {
+		row := make([]byte, cols*cellW)
+		for j := range row {
+			row[j] = ' '
+		}
+		grid[i] = row
+	}
+
+	for _, t := range tiles {
+		x0, y0 := t.GridCol*cellW, t.GridRow*cellH
+		w, h := t.UnitsW*cellW, t.UnitsH*cellH
+		drawBox(grid, x0, y0, w, h, t.CT)
+	}
+
+	out := make([]byte, 0, len(grid)*(cols*cellW+1))
+	for _, row := range grid {
+		out = append(out, row...)
+		out = append(out, '\n')
+	}
+
+	path := filepath.Join(outDir, "manifest.txt")
+	if err := os.WriteFile(path, out, 0644); err != nil {
+		return fmt.Errorf("writing %q: %w", path, err)
+	}
+	return nil
+}
+
+// drawBox draws a w×h box of '+'/'-'/'|' at (x0, y0) in grid, with the
+// canvas type ct labelled in its interior.
+func drawBox(grid [][]byte, x0, y0, w, h int, ct byte) {
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			corner := (x == 0 || x == w-1) && (y == 0 || y == h-1)
+			var ch byte
+			switch {
+			case corner:
+				ch = '+'
+			case y == 0 || y == h-1:
+				ch = '-'
+			case x == 0 || x == w-1:
+				ch = '|'
+			default:
+				continue
+			}
+			grid[y0+y][x0+x] = ch
+		}
+	}
+	label := []byte(fmt.Sprintf("CT%d", ct))
+	ly, lx := y0+h/2, x0+(w-len(label))/2
+	if lx < x0+1 {
+		lx = x0 + 1
+	}
+	for i, c := range label {
+		if lx+i >= x0+w-1 {
+			break
+		}
+		grid[ly][lx+i] = c
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Tnze/go-mc/nbt"
+	"golang.org/x/image/bmp"
+)
+
+// paintNBT mirrors the fields of nbtDataStruct relevant to reconstruction.
+// Pixels must be []int32: go-mc/nbt decodes TagIntArray into []int or
+// []int32 only, even though exportTile encodes it from a []uint32 (the
+// two are bit-for-bit identical, just reinterpreted).
+type paintNBT struct {
+	CT     byte    `nbt:"ct"`
+	Pixels []int32 `nbt:"pixels"`
+}
+
+// decodeDir reconstructs the original composite image from a directory of
+// .paint files, using manifest.json (written alongside them by
+// writeBuildPlan) to recover each tile's GridRow/GridCol/UnitsW/UnitsH.
+// Placing tiles by absolute 16px-unit grid coordinates, rather than
+// re-deriving row bands from filenames, is required for correctness:
+// under -pack optimal a 2-tall canvas can span into the next distinct
+// RowIndex group, so stacking row heights sequentially would overlap or
+// misplace tiles.
+func decodeDir(dir string) (image.Image, error) {
+	tiles, err := readManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(tiles) == 0 {
+		return nil, fmt.Errorf("manifest.json in %q lists no tiles", dir)
+	}
+
+	canvasByCT := make(map[byte]Canvas, len(canvasTypes))
+	for _, can := range canvasTypes {
+		canvasByCT[can.CT] = can
+	}
+
+	rows, cols := 0, 0
+	for _, t := range tiles {
+		if r := t.GridRow + t.UnitsH; r > rows {
+			rows = r
+		}
+		if c := t.GridCol + t.UnitsW; c > cols {
+			cols = c
+		}
+	}
+
+	const unitPx = 16
+	img := image.NewRGBA(image.Rect(0, 0, cols*unitPx, rows*unitPx))
+	for _, t := range tiles {
+		can, ok := canvasByCT[t.CT]
+		if !ok {
+			return nil, fmt.Errorf("%q: unknown canvas type %d", t.FileBase, t.CT)
+		}
+
+		data, err := readPaint(filepath.Join(dir, t.FileBase+".paint"))
+		if err != nil {
+			return nil, fmt.Errorf("reading %q: %w", t.FileBase+".paint", err)
+		}
+		if len(data.Pixels) != can.PxW*can.PxH {
+			return nil, fmt.Errorf("%q: expected %d pixels for canvas type %d, got %d", t.FileBase, can.PxW*can.PxH, t.CT, len(data.Pixels))
+		}
+
+		x0, y0 := t.GridCol*unitPx, t.GridRow*unitPx
+		for ty := 0; ty < can.PxH; ty++ {
+			for tx := 0; tx < can.PxW; tx++ {
+				img.Set(x0+tx, y0+ty, argbColor(data.Pixels[ty*can.PxW+tx]))
+			}
+		}
+	}
+
+	return img, nil
+}
+
+// readManifest loads manifest.json from dir, the grid placement metadata
+// writeBuildPlan emits alongside a directory of .paint files.
+func readManifest(dir string) ([]ManifestTile, error) {
+	path := filepath.Join(dir, "manifest.json")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading %q: %w (decode requires the manifest.json written by export)", path, err)
+	}
+	var tiles []ManifestTile
+	if err := json.Unmarshal(data, &tiles); err != nil {
+		return nil, fmt.Errorf("parsing %q: %w", path, err)
+	}
+	return tiles, nil
+}
+
+// argbColor unpacks a 0xAARRGGBB pixel, as stored in .paint NBT data,
+// into a color.Color.
+func argbColor(p int32) color.RGBA {
+	u := uint32(p)
+	return color.RGBA{
+		R: uint8(u >> 16),
+		G: uint8(u >> 8),
+		B: uint8(u),
+		A: uint8(u >> 24),
+	}
+}
+
+// readPaint opens and decodes a gzip-wrapped .paint file into its NBT
+// payload.
+func readPaint(path string) (paintNBT, error) {
+	var data paintNBT
+	f, err := os.Open(path)
+	if err != nil {
+		return data, fmt.Errorf("opening %q: %w", path, err)
+	}
+	defer f.Close()
+
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		return data, fmt.Errorf("ungzipping %q: %w", path, err)
+	}
+	defer gr.Close()
+
+	if _, err := nbt.NewDecoder(gr).Decode(&data); err != nil {
+		return data, fmt.Errorf("decoding nbt %q: %w", path, err)
+	}
+	return data, nil
+}
+
+// writeDecodedImage writes img to path as PNG or BMP, chosen by the
+// path's extension (default PNG).
+func writeDecodedImage(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("creating %q: %w", path, err)
+	}
+	defer f.Close()
+
+	if strings.EqualFold(filepath.Ext(path), ".bmp") {
+		return bmp.Encode(f, img)
+	}
+	return png.Encode(f, img)
+}
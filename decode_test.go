@@ -0,0 +1,81 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+
+	"github.com/foresturquhart/mc-jop-subdivider/quantize"
+)
+
+// TestDecodeDirRoundTrip exports a greedy tile plan to a temp directory
+// and reconstructs it with decodeDir, checking that every tile's region
+// in the reconstructed image matches the flat color painted into the
+// source image at that tile's grid position. The source is painted with
+// colors taken directly from a custom palette, so DitherNone quantization
+// is lossless and the round trip is exact.
+func TestDecodeDirRoundTrip(t *testing.T) {
+	rows, cols := 3, 3
+	tileColors := []quantize.Color{
+		{R: 10, G: 20, B: 30},
+		{R: 40, G: 50, B: 60},
+		{R: 70, G: 80, B: 90},
+		{R: 100, G: 110, B: 120},
+		{R: 130, G: 140, B: 150},
+		{R: 160, G: 170, B: 180},
+		{R: 190, G: 200, B: 210},
+		{R: 220, G: 230, B: 240},
+		{R: 5, G: 15, B: 25},
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, cols*16, rows*16))
+	for r := 0; r < rows; r++ {
+		for c := 0; c < cols; c++ {
+			col := tileColors[r*cols+c]
+			draw.Draw(img, image.Rect(c*16, r*16, c*16+16, r*16+16), &image.Uniform{
+				C: color.RGBA{R: col.R, G: col.G, B: col.B, A: 0xFF},
+			}, image.Point{}, draw.Src)
+		}
+	}
+
+	plan, err := makeTilePlanGreedy(img, rows, cols, "root", 0, 0)
+	if err != nil {
+		t.Fatalf("makeTilePlanGreedy: %v", err)
+	}
+
+	cfg := Config{
+		OutDir:   t.TempDir(),
+		NameRoot: "root",
+		Author:   "tester",
+		Title:    "roundtrip",
+		Palette:  quantize.New(tileColors),
+		Dither:   quantize.DitherNone,
+		Jobs:     2,
+	}
+
+	names, err := exportAll(cfg, plan)
+	if err != nil {
+		t.Fatalf("exportAll: %v", err)
+	}
+	if err := writeBuildPlan(cfg, plan, names); err != nil {
+		t.Fatalf("writeBuildPlan: %v", err)
+	}
+
+	got, err := decodeDir(cfg.OutDir)
+	if err != nil {
+		t.Fatalf("decodeDir: %v", err)
+	}
+
+	for r := 0; r < rows; r++ {
+		for c := 0; c < cols; c++ {
+			want := tileColors[r*cols+c]
+			x, y := c*16+8, r*16+8
+			gr, gg, gb, _ := got.At(x, y).RGBA()
+			got8 := quantize.Color{R: uint8(gr >> 8), G: uint8(gg >> 8), B: uint8(gb >> 8)}
+			if got8 != want {
+				t.Fatalf("grid (%d,%d) at px (%d,%d): got %+v, want %+v", r, c, x, y, got8, want)
+			}
+		}
+	}
+}
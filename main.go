@@ -11,11 +11,16 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/Tnze/go-mc/nbt"
 	"golang.org/x/image/bmp"
+
+	"github.com/foresturquhart/mc-jop-subdivider/quantize"
+	"github.com/foresturquhart/mc-jop-subdivider/stitch"
 )
 
 // UUID constant for Joy of Painting mod
@@ -23,12 +28,25 @@ const paintingUUID = "d1ebe29f-f4e9-4572-83cd-8b2cdbfc2420"
 
 // Config holds CLI configuration and global naming parameters.
 type Config struct {
-	InputPath string
-	Author    string
-	Title     string
-	OutDir    string
-	NameRoot  string
-	BaseID    int64
+	InputPath         string
+	InputDir          string
+	ManifestPath      string
+	Blend             stitch.BlendMode
+	Author            string
+	Title             string
+	OutDir            string
+	NameRoot          string
+	BaseID            int64
+	PackMode          string
+	Costs             [4]float64
+	Palette           *quantize.Palette
+	Dither            quantize.DitherMode
+	DitherAcrossTiles bool
+	Jobs              int
+	DecodeDir         string
+	DecodeOut         string
+	Margin            int
+	Spacing           int
 }
 
 // Canvas represents a tile size in pixels and 16px units for placement.
@@ -53,6 +71,12 @@ type Tile struct {
 	FileBase  string
 	TileIndex int
 	RowIndex  int
+	GridRow   int
+	GridCol   int
+	UnitsW    int
+	UnitsH    int
+	SrcX      int
+	SrcY      int
 }
 
 // nbtDataStruct defines the structure encoded into .paint files.
@@ -76,19 +100,36 @@ func main() {
 func run() error {
 	cfg := parseFlags()
 
-	img, err := loadImage(cfg.InputPath)
+	if cfg.DecodeDir != "" {
+		img, err := decodeDir(cfg.DecodeDir)
+		if err != nil {
+			return err
+		}
+		if err := writeDecodedImage(cfg.DecodeOut, img); err != nil {
+			return fmt.Errorf("writing %q: %w", cfg.DecodeOut, err)
+		}
+		log.Printf("Reconstructed %s from %s", cfg.DecodeOut, cfg.DecodeDir)
+		return nil
+	}
+
+	img, err := loadInput(cfg)
 	if err != nil {
 		return err
 	}
 
 	bounds := img.Bounds()
 	w, h := bounds.Dx(), bounds.Dy()
-	if w%16 != 0 || h%16 != 0 {
-		return fmt.Errorf("image dimensions must be multiples of 16: got %dx%d", w, h)
+	pitch := 16 + cfg.Spacing
+	cols, rows := (w-2*cfg.Margin+cfg.Spacing)/pitch, (h-2*cfg.Margin+cfg.Spacing)/pitch
+	if (w-2*cfg.Margin+cfg.Spacing)%pitch != 0 || (h-2*cfg.Margin+cfg.Spacing)%pitch != 0 || cols < 1 || rows < 1 {
+		return fmt.Errorf("image dimensions incompatible with -margin %d -spacing %d: got %dx%d", cfg.Margin, cfg.Spacing, w, h)
+	}
+
+	if cfg.DitherAcrossTiles {
+		img = quantize.QuantizeImage(img, cfg.Palette, cfg.Dither)
 	}
 
-	cols, rows := w/16, h/16
-	plan, err := MakeTilePlan(img, rows, cols, cfg.NameRoot)
+	plan, err := MakeTilePlan(img, rows, cols, cfg.NameRoot, cfg.PackMode, cfg.Costs, cfg.Margin, cfg.Spacing)
 	if err != nil {
 		return err
 	}
@@ -97,42 +138,147 @@ func run() error {
 		return fmt.Errorf("creating output dir: %w", err)
 	}
 
-	var counter int64
-	for _, tile := range plan {
-		if err := exportTile(cfg, tile, counter); err != nil {
-			return fmt.Errorf("exporting tile %q: %w", tile.FileBase, err)
-		}
-		counter++
-		log.Printf("Exported %s (\"%s X %d Y %d\" by %s)", tile.FileBase, cfg.Title, tile.RowIndex, tile.TileIndex, cfg.Author)
+	names, err := exportAll(cfg, plan)
+	if err != nil {
+		return err
 	}
-	return nil
+
+	return writeBuildPlan(cfg, plan, names)
 }
 
 // parseFlags reads CLI arguments and populates Config.
 func parseFlags() Config {
 	input := flag.String("input", "", "Path to input image (bmp, png, jpeg)")
+	inputDir := flag.String("input-dir", "", "Directory of source images to stitch, used with -manifest instead of -input")
+	manifestPath := flag.String("manifest", "", "JSON manifest of (x,y) offsets for -input-dir")
+	blend := flag.String("blend", "overwrite", "Overlap blend for -input-dir: overwrite, average, or median")
 	author := flag.String("author", "Unknown", "Author name for .paint files")
 	title := flag.String("title", "Untitled", "Title for .paint files")
 	out := flag.String("out", "tiles", "Output directory for tiles and .paint files")
+	pack := flag.String("pack", "greedy", "Packing strategy: greedy or optimal")
+	cost := flag.String("cost", "1,1,1,1", "Comma-separated per-canvas cost for -pack optimal, indexed by CT (ct0,ct1,ct2,ct3)")
+	palettePath := flag.String("palette", "", "Path to a .hex or .gpl palette file (default: built-in Minecraft map-color palette)")
+	dither := flag.String("dither", "none", "Color quantization mode: none or fs (Floyd-Steinberg)")
+	ditherAcrossTiles := flag.Bool("dither-across-tiles", false, "Diffuse dithering error across the whole image instead of per-tile")
+	jobs := flag.Int("jobs", runtime.NumCPU(), "Number of tiles to export concurrently")
+	decode := flag.String("decode", "", "Reconstruct a composite image from a directory of .paint files, instead of subdividing -input")
+	decodeOut := flag.String("decode-out", "reconstructed.png", "Output path for -decode (.png or .bmp, by extension)")
+	margin := flag.Int("margin", 0, "Pixels of outer border to skip before tiling, for tileset-style input images")
+	spacing := flag.Int("spacing", 0, "Pixel gutter between 16x16 logical tiles, for tileset-style input images")
 	flag.Parse()
 
-	if *input == "" {
-		log.Fatal("missing input file: use -input <path>")
+	if *decode != "" {
+		return Config{DecodeDir: *decode, DecodeOut: *decodeOut}
+	}
+
+	if *margin < 0 {
+		log.Fatalf("invalid -margin %d: must not be negative", *margin)
+	}
+	if *spacing < 0 {
+		log.Fatalf("invalid -spacing %d: must not be negative", *spacing)
+	}
+
+	if *inputDir != "" {
+		if *manifestPath == "" {
+			log.Fatal("missing -manifest: required when using -input-dir")
+		}
+		if *input != "" {
+			log.Fatal("use either -input or -input-dir, not both")
+		}
+	} else if *input == "" {
+		log.Fatal("missing input file: use -input <path> or -input-dir with -manifest")
+	}
+
+	blendMode, ok := stitch.ParseBlendMode(*blend)
+	if !ok {
+		log.Fatalf("invalid -blend %q: must be overwrite, average, or median", *blend)
+	}
+
+	if *pack != "greedy" && *pack != "optimal" {
+		log.Fatalf("invalid -pack %q: must be greedy or optimal", *pack)
+	}
+
+	if *jobs < 1 {
+		log.Fatalf("invalid -jobs %d: must be at least 1", *jobs)
+	}
+
+	costs, err := parseCosts(*cost)
+	if err != nil {
+		log.Fatalf("invalid -cost: %v", err)
+	}
+
+	ditherMode, ok := quantize.ParseDitherMode(*dither)
+	if !ok {
+		log.Fatalf("invalid -dither %q: must be none or fs", *dither)
+	}
+
+	pal := quantize.Default()
+	if *palettePath != "" {
+		pal, err = quantize.Load(*palettePath)
+		if err != nil {
+			log.Fatalf("loading -palette: %v", err)
+		}
 	}
 
-	base := filepath.Base(*input)
+	nameSource := *input
+	if *inputDir != "" {
+		nameSource = *inputDir
+	}
+	base := filepath.Base(nameSource)
 	nameRoot := strings.TrimSuffix(base, filepath.Ext(base))
 
 	return Config{
-		InputPath: *input,
-		Author:    *author,
-		Title:     *title,
-		OutDir:    *out,
-		NameRoot:  nameRoot,
-		BaseID:    time.Now().UnixNano(),
+		InputPath:         *input,
+		InputDir:          *inputDir,
+		ManifestPath:      *manifestPath,
+		Blend:             blendMode,
+		Author:            *author,
+		Title:             *title,
+		OutDir:            *out,
+		NameRoot:          nameRoot,
+		BaseID:            time.Now().UnixNano(),
+		PackMode:          *pack,
+		Costs:             costs,
+		Palette:           pal,
+		Dither:            ditherMode,
+		DitherAcrossTiles: *ditherAcrossTiles,
+		Jobs:              *jobs,
+		Margin:            *margin,
+		Spacing:           *spacing,
 	}
 }
 
+// parseCosts parses a "ct0,ct1,ct2,ct3" cost list into [4]float64.
+func parseCosts(s string) ([4]float64, error) {
+	var costs [4]float64
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return costs, fmt.Errorf("expected 4 comma-separated values, got %d", len(parts))
+	}
+	for i, p := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return costs, fmt.Errorf("parsing cost %q: %w", p, err)
+		}
+		costs[i] = v
+	}
+	return costs, nil
+}
+
+// loadInput builds the source image, either by decoding a single file
+// (-input) or by stitching a directory of source images according to a
+// manifest (-input-dir / -manifest).
+func loadInput(cfg Config) (image.Image, error) {
+	if cfg.InputDir != "" {
+		manifest, err := stitch.LoadManifest(cfg.ManifestPath)
+		if err != nil {
+			return nil, err
+		}
+		return stitch.New(cfg.InputDir, manifest, cfg.Blend)
+	}
+	return loadImage(cfg.InputPath)
+}
+
 // loadImage opens and decodes an image from the disk.
 func loadImage(path string) (image.Image, error) {
 	f, err := os.Open(path)
@@ -183,8 +329,22 @@ func (o OccGrid) Mark(r, c, h, w int) {
 	}
 }
 
-// MakeTilePlan computes tiling positions for an image.
-func MakeTilePlan(img image.Image, rows, cols int, nameRoot string) ([]Tile, error) {
+// MakeTilePlan computes tiling positions for an image using the requested
+// packing strategy. "optimal" minimises total cost (see makeTilePlanOptimal);
+// anything else falls back to the greedy row-scan strategy. margin and
+// spacing describe a tileset-style source image: margin pixels are
+// skipped around the outside, and spacing pixels separate each 16x16
+// logical tile.
+func MakeTilePlan(img image.Image, rows, cols int, nameRoot string, mode string, costs [4]float64, margin, spacing int) ([]Tile, error) {
+	if mode == "optimal" {
+		return makeTilePlanOptimal(img, rows, cols, nameRoot, costs, margin, spacing)
+	}
+	return makeTilePlanGreedy(img, rows, cols, nameRoot, margin, spacing)
+}
+
+// makeTilePlanGreedy computes tiling positions by always picking the
+// largest canvas that fits at the current lowest-leftmost empty cell.
+func makeTilePlanGreedy(img image.Image, rows, cols int, nameRoot string, margin, spacing int) ([]Tile, error) {
 	occ := NewOccGrid(rows, cols)
 	var tiles []Tile
 
@@ -216,8 +376,8 @@ func MakeTilePlan(img image.Image, rows, cols int, nameRoot string) ([]Tile, err
 			}
 
 			occ.Mark(r, c, sel.UnitsH, sel.UnitsW)
-			x0, y0 := c*16, r*16
-			sub := crop(img, x0, y0, sel.PxW, sel.PxH)
+			x0, y0 := margin+c*(16+spacing), margin+r*(16+spacing)
+			sub := cropUnits(img, x0, y0, sel.UnitsW, sel.UnitsH, spacing)
 			fileBase := fmt.Sprintf("%s_%d_%d", nameRoot, rowIndex, tileIndex)
 			tiles = append(tiles, Tile{
 				Img:       sub,
@@ -225,6 +385,12 @@ func MakeTilePlan(img image.Image, rows, cols int, nameRoot string) ([]Tile, err
 				FileBase:  fileBase,
 				TileIndex: tileIndex,
 				RowIndex:  rowIndex,
+				GridRow:   r,
+				GridCol:   c,
+				UnitsW:    sel.UnitsW,
+				UnitsH:    sel.UnitsH,
+				SrcX:      x0,
+				SrcY:      y0,
 			})
 			hasValidTileInRow = true
 			tileIndex++
@@ -237,23 +403,32 @@ func MakeTilePlan(img image.Image, rows, cols int, nameRoot string) ([]Tile, err
 	return tiles, nil
 }
 
-// exportTile writes BMP and .paint files for a Tile.
-func exportTile(cfg Config, tile Tile, counter int64) error {
+// exportTile writes BMP and .paint files for a Tile and returns the Name
+// UUID assigned to it, for the build-plan manifest. Unless the whole
+// image was already quantized by -dither-across-tiles, each tile is
+// quantized to cfg.Palette independently, so Floyd-Steinberg error
+// diffusion respects the 16px tile seams the in-game renderer uses.
+func exportTile(cfg Config, tile Tile, counter int64) (string, error) {
+	img := tile.Img
+	if !cfg.DitherAcrossTiles {
+		img = quantize.QuantizeImage(img, cfg.Palette, cfg.Dither)
+	}
+
 	// BMP
 	bmpPath := filepath.Join(cfg.OutDir, tile.FileBase+".bmp")
-	if err := writeBMP(bmpPath, tile.Img); err != nil {
-		return err
+	if err := writeBMP(bmpPath, img); err != nil {
+		return "", err
 	}
 
 	// Build pixel data
-	h := tile.Img.Bounds().Dy()
-	w := tile.Img.Bounds().Dx()
+	h := img.Bounds().Dy()
+	w := img.Bounds().Dx()
 	pixels := make([]uint32, w*h)
 	alpha := uint32(0xFF) << 24
 	idx := 0
 	for y := range h {
 		for x := range w {
-			r8, g8, b8, _ := tile.Img.At(x, y).RGBA()
+			r8, g8, b8, _ := img.At(x, y).RGBA()
 			pixels[idx] = alpha |
 				uint32(uint8(r8>>8))<<16 |
 				uint32(uint8(g8>>8))<<8 |
@@ -263,6 +438,7 @@ func exportTile(cfg Config, tile Tile, counter int64) error {
 	}
 
 	// NBT
+	name := fmt.Sprintf("%s_%d", paintingUUID, cfg.BaseID+counter)
 	nbtData := nbtDataStruct{
 		Generation: 1,
 		CT:         tile.CT,
@@ -270,14 +446,14 @@ func exportTile(cfg Config, tile Tile, counter int64) error {
 		V:          2,
 		Author:     cfg.Author,
 		Title:      cfg.Title,
-		Name:       fmt.Sprintf("%s_%d", paintingUUID, cfg.BaseID+counter),
+		Name:       name,
 	}
 	paintPath := filepath.Join(cfg.OutDir, tile.FileBase+".paint")
 	if err := writePaint(paintPath, nbtData); err != nil {
-		return err
+		return "", err
 	}
 
-	return nil
+	return name, nil
 }
 
 // writeBMP encodes and writes an image as BMP.
@@ -311,9 +487,30 @@ func writePaint(path string, data nbtDataStruct) error {
 
 // crop returns an RGBA sub-image of given dimensions.
 func crop(img image.Image, x, y, w, h int) image.Image {
+	min := img.Bounds().Min
 	r := image.Rect(0, 0, w, h)
 	sub := image.NewRGBA(r)
-	dx := image.Rect(x, y, x+w, y+h)
+	dx := image.Rect(min.X+x, min.Y+y, min.X+x+w, min.Y+y+h)
 	draw.Draw(sub, r, img, dx.Min, draw.Src)
 	return sub
 }
+
+// cropUnits assembles a unitsW x unitsH block of 16px units starting at
+// (x0, y0), skipping spacing pixels of gutter between each unit. With
+// spacing 0 it behaves like a plain crop of the contiguous region.
+func cropUnits(img image.Image, x0, y0, unitsW, unitsH, spacing int) image.Image {
+	if spacing == 0 {
+		return crop(img, x0, y0, unitsW*16, unitsH*16)
+	}
+	min := img.Bounds().Min
+	r := image.Rect(0, 0, unitsW*16, unitsH*16)
+	sub := image.NewRGBA(r)
+	for uy := 0; uy < unitsH; uy++ {
+		for ux := 0; ux < unitsW; ux++ {
+			sx, sy := x0+ux*(16+spacing), y0+uy*(16+spacing)
+			dst := image.Rect(ux*16, uy*16, ux*16+16, uy*16+16)
+			draw.Draw(sub, dst, img, image.Pt(min.X+sx, min.Y+sy), draw.Src)
+		}
+	}
+	return sub
+}
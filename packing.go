@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"time"
+)
+
+// maxOptimalWidth bounds the grid width (in 16px units) for exact DP
+// packing; wider grids fall back to the greedy strategy rather than risk
+// a state space blowup. The broken-profile state space is exponential in
+// cols (roughly 2^cols states per row), so this is set to a width
+// measured to finish in well under a second on this machine, not just a
+// round number: 14 units took ~230ms and 16 already took ~1.3s against a
+// cost vector chosen to maximize DP branching.
+const maxOptimalWidth = 14
+
+// dpTimeBudget is a hard ceiling on how long the DP is allowed to search
+// before makeTilePlanOptimal gives up and falls back to greedy. This is
+// the actual backstop against a hang/OOM: maxOptimalWidth alone only
+// covers the worst case we happened to measure, and cost vectors or grid
+// shapes we didn't test could still blow up the state space within that
+// width.
+const dpTimeBudget = 2 * time.Second
+
+// dpKey identifies a broken-profile DP state: the row and column cursor
+// being filled, the occupancy bitmask for the current row, and the
+// occupancy bitmask carried down into the next row by 2-tall canvases.
+type dpKey struct {
+	r, c int
+	cur  uint32
+	next uint32
+}
+
+// dpChoice is a memoized minimum-cost result for a dpKey, along with the
+// canvas type that achieves it (needed to reconstruct the plan).
+type dpChoice struct {
+	cost  float64
+	ct    byte
+	valid bool
+}
+
+// makeTilePlanOptimal finds a minimum-cost tiling of the rows×cols grid
+// using a broken-profile DP: state is (row, column, occupancy bitmask of
+// the current row, occupancy bitmask of the next row), and at each free
+// cell we try every canvas type that fits, recursing on the resulting
+// state and keeping whichever choice minimises total cost. Costs are
+// indexed by CT. Grids wider than maxOptimalWidth fall back to the
+// greedy strategy, since the row bitmask is a uint32. The search is also
+// bounded by dpTimeBudget: if it runs that long without finishing (an
+// adversarial cost vector can still blow up the state space within
+// maxOptimalWidth), the DP is abandoned and this also falls back to
+// greedy rather than risk a hang.
+func makeTilePlanOptimal(img image.Image, rows, cols int, nameRoot string, costs [4]float64, margin, spacing int) ([]Tile, error) {
+	if cols > maxOptimalWidth {
+		return makeTilePlanGreedy(img, rows, cols, nameRoot, margin, spacing)
+	}
+
+	canvasByCT := make(map[byte]Canvas, len(canvasTypes))
+	for _, can := range canvasTypes {
+		canvasByCT[can.CT] = can
+	}
+
+	memo := make(map[dpKey]dpChoice)
+	deadline := time.Now().Add(dpTimeBudget)
+	timedOut := false
+	var solve func(r, c int, cur, next uint32) dpChoice
+	solve = func(r, c int, cur, next uint32) dpChoice {
+		if r == rows {
+			return dpChoice{cost: 0, valid: true}
+		}
+		if c == cols {
+			return solve(r+1, 0, next, 0)
+		}
+		if cur&(1<<uint(c)) != 0 {
+			return solve(r, c+1, cur, next)
+		}
+
+		key := dpKey{r, c, cur, next}
+		if choice, ok := memo[key]; ok {
+			return choice
+		}
+		if timedOut {
+			return dpChoice{}
+		}
+		if time.Now().After(deadline) {
+			timedOut = true
+			return dpChoice{}
+		}
+
+		best := dpChoice{}
+		for _, can := range canvasTypes {
+			if c+can.UnitsW > cols || r+can.UnitsH > rows {
+				continue
+			}
+			if !fitsMask(cur, next, c, can.UnitsW, can.UnitsH) {
+				continue
+			}
+			nc, nn := markMask(cur, next, c, can.UnitsW, can.UnitsH)
+			sub := solve(r, c+can.UnitsW, nc, nn)
+			if !sub.valid {
+				continue
+			}
+			cost := sub.cost + costs[can.CT]
+			if !best.valid || cost < best.cost {
+				best = dpChoice{cost: cost, ct: can.CT, valid: true}
+			}
+		}
+		memo[key] = best
+		return best
+	}
+
+	choice := solve(0, 0, 0, 0)
+	if timedOut {
+		return makeTilePlanGreedy(img, rows, cols, nameRoot, margin, spacing)
+	}
+	if !choice.valid {
+		return nil, fmt.Errorf("no valid tiling found for %dx%d grid", rows, cols)
+	}
+
+	return buildPlanFromDP(img, rows, cols, nameRoot, canvasByCT, solve, margin, spacing)
+}
+
+// fitsMask reports whether a can×can canvas of the given unit dimensions
+// fits at column c without overlapping cells already marked in cur (this
+// row) or next (cells pre-claimed by a 2-tall canvas placed earlier).
+func fitsMask(cur, next uint32, c, unitsW, unitsH int) bool {
+	for i := 0; i < unitsW; i++ {
+		bit := uint32(1) << uint(c+i)
+		if cur&bit != 0 {
+			return false
+		}
+		if unitsH == 2 && next&bit != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// markMask returns cur/next with the cells covered by a canvas of the
+// given unit dimensions at column c marked occupied.
+func markMask(cur, next uint32, c, unitsW, unitsH int) (uint32, uint32) {
+	for i := 0; i < unitsW; i++ {
+		bit := uint32(1) << uint(c+i)
+		cur |= bit
+		if unitsH == 2 {
+			next |= bit
+		}
+	}
+	return cur, next
+}
+
+// buildPlanFromDP replays the memoized DP choices to build the actual
+// Tile list, mirroring the bookkeeping (FileBase, RowIndex, TileIndex)
+// of makeTilePlanGreedy.
+func buildPlanFromDP(img image.Image, rows, cols int, nameRoot string, canvasByCT map[byte]Canvas, solve func(r, c int, cur, next uint32) dpChoice, margin, spacing int) ([]Tile, error) {
+	var tiles []Tile
+	rowIndex := 0
+	var cur, next uint32
+	for r := 0; r < rows; r++ {
+		tileIndex := 0
+		hasValidTileInRow := false
+		for c := 0; c < cols; {
+			if cur&(1<<uint(c)) != 0 {
+				c++
+				continue
+			}
+			choice := solve(r, c, cur, next)
+			if !choice.valid {
+				return nil, fmt.Errorf("no canvas fits at %d,%d", r, c)
+			}
+			can := canvasByCT[choice.ct]
+			cur, next = markMask(cur, next, c, can.UnitsW, can.UnitsH)
+
+			x0, y0 := margin+c*(16+spacing), margin+r*(16+spacing)
+			sub := cropUnits(img, x0, y0, can.UnitsW, can.UnitsH, spacing)
+			fileBase := fmt.Sprintf("%s_%d_%d", nameRoot, rowIndex, tileIndex)
+			tiles = append(tiles, Tile{
+				Img:       sub,
+				CT:        can.CT,
+				FileBase:  fileBase,
+				TileIndex: tileIndex,
+				RowIndex:  rowIndex,
+				GridRow:   r,
+				GridCol:   c,
+				UnitsW:    can.UnitsW,
+				UnitsH:    can.UnitsH,
+				SrcX:      x0,
+				SrcY:      y0,
+			})
+			hasValidTileInRow = true
+			tileIndex++
+			c += can.UnitsW
+		}
+		if hasValidTileInRow {
+			rowIndex++
+		}
+		cur, next = next, 0
+	}
+	return tiles, nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"image"
+	"testing"
+	"time"
+)
+
+// planCost sums the per-tile cost of a plan, indexed by CT, matching how
+// makeTilePlanOptimal scores a tiling.
+func planCost(plan []Tile, costs [4]float64) float64 {
+	total := 0.0
+	for _, t := range plan {
+		total += costs[t.CT]
+	}
+	return total
+}
+
+// assertFullCoverage checks that plan's tiles exactly tile the rows×cols
+// grid: every cell is covered by exactly one tile, with no gaps or overlaps.
+func assertFullCoverage(t *testing.T, plan []Tile, rows, cols int) {
+	t.Helper()
+	covered := make([][]bool, rows)
+	for i := range covered {
+		covered[i] = make([]bool, cols)
+	}
+	for _, tile := range plan {
+		for dr := 0; dr < tile.UnitsH; dr++ {
+			for dc := 0; dc < tile.UnitsW; dc++ {
+				r, c := tile.GridRow+dr, tile.GridCol+dc
+				if r >= rows || c >= cols {
+					t.Fatalf("tile at (%d,%d) %dx%d units spills outside %dx%d grid", tile.GridRow, tile.GridCol, tile.UnitsW, tile.UnitsH, rows, cols)
+				}
+				if covered[r][c] {
+					t.Fatalf("cell (%d,%d) covered more than once", r, c)
+				}
+				covered[r][c] = true
+			}
+		}
+	}
+	for r := 0; r < rows; r++ {
+		for c := 0; c < cols; c++ {
+			if !covered[r][c] {
+				t.Fatalf("cell (%d,%d) left uncovered", r, c)
+			}
+		}
+	}
+}
+
+// TestMakeTilePlanOptimalBeatsOrMatchesGreedy checks the DP packer's core
+// correctness property: for a cost vector that penalizes greedy's
+// largest-first choice, the optimal plan must cost no more than greedy's.
+func TestMakeTilePlanOptimalBeatsOrMatchesGreedy(t *testing.T) {
+	rows, cols := 4, 4
+	img := image.NewRGBA(image.Rect(0, 0, cols*20, rows*20))
+	// CT1 (2x2) is expensive; CT3 (1x2) is cheap, so the optimal plan
+	// should avoid CT1 entirely even though greedy always prefers it.
+	costs := [4]float64{1000, 1000, 1, 1000}
+
+	greedy, err := makeTilePlanGreedy(img, rows, cols, "root", 0, 0)
+	if err != nil {
+		t.Fatalf("greedy: %v", err)
+	}
+	assertFullCoverage(t, greedy, rows, cols)
+
+	optimal, err := makeTilePlanOptimal(img, rows, cols, "root", costs, 0, 0)
+	if err != nil {
+		t.Fatalf("optimal: %v", err)
+	}
+	assertFullCoverage(t, optimal, rows, cols)
+
+	greedyCost := planCost(greedy, costs)
+	optimalCost := planCost(optimal, costs)
+	if optimalCost > greedyCost {
+		t.Fatalf("optimal plan cost %v should never exceed greedy plan cost %v", optimalCost, greedyCost)
+	}
+	for _, tile := range optimal {
+		if tile.CT == 1 {
+			t.Fatalf("expected optimal plan to avoid the expensive CT1 canvas entirely, found one at (%d,%d)", tile.GridRow, tile.GridCol)
+		}
+	}
+}
+
+// TestMakeTilePlanOptimalFallsBackBeyondMaxWidth checks that grids wider
+// than maxOptimalWidth skip the DP entirely and still return a valid,
+// fully-covering plan via the greedy fallback.
+func TestMakeTilePlanOptimalFallsBackBeyondMaxWidth(t *testing.T) {
+	rows, cols := 3, maxOptimalWidth+4
+	img := image.NewRGBA(image.Rect(0, 0, cols*20, rows*20))
+
+	plan, err := makeTilePlanOptimal(img, rows, cols, "root", [4]float64{1, 1, 1, 1}, 0, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertFullCoverage(t, plan, rows, cols)
+}
+
+// TestMakeTilePlanOptimalTerminatesPromptly is a regression guard against
+// the DP state space blowing up within maxOptimalWidth: even with a cost
+// vector chosen to maximize DP branching, packing must finish (via either
+// the exact search or the dpTimeBudget fallback) well within a few
+// seconds, never hang or exhaust memory.
+func TestMakeTilePlanOptimalTerminatesPromptly(t *testing.T) {
+	rows, cols := 5, maxOptimalWidth
+	img := image.NewRGBA(image.Rect(0, 0, cols*20, rows*20))
+
+	start := time.Now()
+	plan, err := makeTilePlanOptimal(img, rows, cols, "root", [4]float64{1000, 1000, 1, 1000}, 0, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Fatalf("packing a %dx%d grid took %v, expected it to finish within the time budget", rows, cols, elapsed)
+	}
+	assertFullCoverage(t, plan, rows, cols)
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// tileJob pairs a Tile with the painting counter it was assigned, decided
+// up front by the single-threaded dispatcher so the tile-to-counter
+// mapping is fixed regardless of which worker happens to process it.
+type tileJob struct {
+	tile    Tile
+	counter int64
+}
+
+// exportResult carries a worker's outcome back to the log-draining
+// goroutine, tagged with the counter it was assigned.
+type exportResult struct {
+	counter  int64
+	fileBase string
+	name     string
+	logLine  string
+}
+
+// exportAll dispatches exportTile across a pool of cfg.Jobs workers. A
+// bounded channel of tileJob values feeds the workers; the dispatcher
+// assigns each tile its painting counter serially, in plan order, before
+// sending it, so Name UUIDs and their tile mapping stay deterministic
+// even though tiles aren't processed in plan order. Workers report
+// (counter, logLine) on a result channel that a single goroutine drains
+// in counter order, so log output stays deterministic regardless of
+// scheduling. The first error cancels the remaining work. On success,
+// exportAll returns the FileBase -> Name UUID mapping assigned during
+// export, for the build-plan manifest.
+func exportAll(cfg Config, plan []Tile) (map[string]string, error) {
+	jobs := make(chan tileJob, cfg.Jobs)
+	results := make(chan exportResult)
+
+	g, ctx := errgroup.WithContext(context.Background())
+
+	for i := 0; i < cfg.Jobs; i++ {
+		g.Go(func() error {
+			for {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case job, ok := <-jobs:
+					if !ok {
+						return nil
+					}
+					tile := job.tile
+					name, err := exportTile(cfg, tile, job.counter)
+					if err != nil {
+						return fmt.Errorf("exporting tile %q: %w", tile.FileBase, err)
+					}
+					logLine := fmt.Sprintf("Exported %s (\"%s X %d Y %d\" by %s)", tile.FileBase, cfg.Title, tile.RowIndex, tile.TileIndex, cfg.Author)
+					select {
+					case results <- exportResult{counter: job.counter, fileBase: tile.FileBase, name: name, logLine: logLine}:
+					case <-ctx.Done():
+						return ctx.Err()
+					}
+				}
+			}
+		})
+	}
+
+	g.Go(func() error {
+		defer close(jobs)
+		for n, tile := range plan {
+			select {
+			case jobs <- tileJob{tile: tile, counter: int64(n)}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		return nil
+	})
+
+	names := make(map[string]string, len(plan))
+	drainDone := make(chan struct{})
+	go func() {
+		defer close(drainDone)
+		pending := make(map[int64]exportResult)
+		next := int64(0)
+		for res := range results {
+			pending[res.counter] = res
+			for {
+				r, ok := pending[next]
+				if !ok {
+					break
+				}
+				log.Println(r.logLine)
+				names[r.fileBase] = r.name
+				delete(pending, next)
+				next++
+			}
+		}
+	}()
+
+	err := g.Wait()
+	close(results)
+	<-drainDone
+	if err != nil {
+		return nil, err
+	}
+	return names, nil
+}
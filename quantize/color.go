@@ -0,0 +1,45 @@
+// Package quantize maps arbitrary image colors onto a fixed palette (by
+// default, Minecraft's map-color palette), with optional Floyd-Steinberg
+// error diffusion.
+package quantize
+
+import "math"
+
+// Color is a simple 8-bit-per-channel RGB triple.
+type Color struct {
+	R, G, B uint8
+}
+
+// srgbToLinear converts an 8-bit sRGB channel value to linear light.
+func srgbToLinear(c uint8) float64 {
+	v := float64(c) / 255
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+// linearToOklab converts linear sRGB to the Oklab perceptual color space.
+func linearToOklab(r, g, b float64) [3]float64 {
+	l := 0.4122214708*r + 0.5363325363*g + 0.0514459929*b
+	m := 0.2119034982*r + 0.6806995451*g + 0.1073969566*b
+	s := 0.0883024619*r + 0.2817188376*g + 0.6299787005*b
+
+	l = math.Cbrt(l)
+	m = math.Cbrt(m)
+	s = math.Cbrt(s)
+
+	return [3]float64{
+		0.2104542553*l + 0.7936177850*m - 0.0040720468*s,
+		1.9779984951*l - 2.4285922050*m + 0.4505937099*s,
+		0.0259040371*l + 0.7827717662*m - 0.8086757660*s,
+	}
+}
+
+// toOklab converts a Color to its Oklab representation.
+func toOklab(c Color) [3]float64 {
+	r := srgbToLinear(c.R)
+	g := srgbToLinear(c.G)
+	b := srgbToLinear(c.B)
+	return linearToOklab(r, g, b)
+}
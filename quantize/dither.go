@@ -0,0 +1,118 @@
+package quantize
+
+import "image"
+
+// DitherMode selects how pixels are mapped onto a Palette.
+type DitherMode int
+
+const (
+	// DitherNone maps each pixel to its nearest palette color independently.
+	DitherNone DitherMode = iota
+	// DitherFloydSteinberg diffuses quantization error to neighbouring
+	// pixels in raster order.
+	DitherFloydSteinberg
+)
+
+// ParseDitherMode parses the -dither flag value.
+func ParseDitherMode(s string) (DitherMode, bool) {
+	switch s {
+	case "none", "":
+		return DitherNone, true
+	case "fs":
+		return DitherFloydSteinberg, true
+	default:
+		return DitherNone, false
+	}
+}
+
+// floydSteinbergOffsets are the (dx, dy, weight/16) targets for
+// distributing quantization error in raster order.
+var floydSteinbergOffsets = []struct {
+	dx, dy int
+	weight float64
+}{
+	{1, 0, 7.0 / 16},
+	{-1, 1, 3.0 / 16},
+	{0, 1, 5.0 / 16},
+	{1, 1, 1.0 / 16},
+}
+
+// QuantizeImage maps every pixel of img onto the nearest color in pal,
+// returning a new RGBA image the size of img.Bounds(). With
+// DitherFloydSteinberg, quantization error is diffused to neighbouring
+// pixels within img's bounds, so callers control the diffusion region by
+// the image they pass in (a single tile, or the whole canvas).
+func QuantizeImage(img image.Image, pal *Palette, mode DitherMode) *image.RGBA {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	out := image.NewRGBA(image.Rect(0, 0, w, h))
+
+	if mode != DitherFloydSteinberg {
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				c := pal.Nearest(colorAt(img, bounds, x, y))
+				setRGBA(out, x, y, c)
+			}
+		}
+		return out
+	}
+
+	// Float working buffer so error diffusion doesn't compound clamping
+	// artifacts across passes.
+	buf := make([][3]float64, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := colorAt(img, bounds, x, y)
+			buf[y*w+x] = [3]float64{float64(c.R), float64(c.G), float64(c.B)}
+		}
+	}
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			old := buf[y*w+x]
+			oldColor := Color{R: clamp8(old[0]), G: clamp8(old[1]), B: clamp8(old[2])}
+			newColor := pal.Nearest(oldColor)
+			setRGBA(out, x, y, newColor)
+
+			errR := old[0] - float64(newColor.R)
+			errG := old[1] - float64(newColor.G)
+			errB := old[2] - float64(newColor.B)
+
+			for _, off := range floydSteinbergOffsets {
+				nx, ny := x+off.dx, y+off.dy
+				if nx < 0 || nx >= w || ny < 0 || ny >= h {
+					continue
+				}
+				i := ny*w + nx
+				buf[i][0] += errR * off.weight
+				buf[i][1] += errG * off.weight
+				buf[i][2] += errB * off.weight
+			}
+		}
+	}
+
+	return out
+}
+
+func colorAt(img image.Image, bounds image.Rectangle, x, y int) Color {
+	r8, g8, b8, _ := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+	return Color{R: uint8(r8 >> 8), G: uint8(g8 >> 8), B: uint8(b8 >> 8)}
+}
+
+func setRGBA(out *image.RGBA, x, y int, c Color) {
+	i := out.PixOffset(x, y)
+	out.Pix[i] = c.R
+	out.Pix[i+1] = c.G
+	out.Pix[i+2] = c.B
+	out.Pix[i+3] = 0xFF
+}
+
+func clamp8(v float64) uint8 {
+	if v < 0 {
+		return 0
+	}
+	if v > 255 {
+		return 255
+	}
+	return uint8(v)
+}
@@ -0,0 +1,79 @@
+package quantize
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// solidGray returns a w×h image filled with a single gray value.
+func solidGray(w, h int, v uint8) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{R: v, G: v, B: v, A: 0xFF})
+		}
+	}
+	return img
+}
+
+func countColor(img *image.RGBA, c Color) int {
+	bounds := img.Bounds()
+	n := 0
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, _ := img.At(x, y).RGBA()
+			if uint8(r>>8) == c.R && uint8(g>>8) == c.G && uint8(b>>8) == c.B {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// TestQuantizeImageNoDither checks that every pixel independently maps to
+// its nearest palette color, with no error carried between pixels: a
+// uniform mid-gray image against a black/white palette should come out
+// entirely one color, since every pixel sees the same, unmodified input.
+func TestQuantizeImageNoDither(t *testing.T) {
+	pal := New([]Color{{0, 0, 0}, {255, 255, 255}})
+	img := solidGray(8, 1, 60)
+
+	out := QuantizeImage(img, pal, DitherNone)
+
+	black := countColor(out, Color{0, 0, 0})
+	white := countColor(out, Color{255, 255, 255})
+	if black != 8 || white != 0 {
+		t.Fatalf("expected all 8 pixels to map to black (nearest to gray 60 in Oklab), got black=%d white=%d", black, white)
+	}
+}
+
+// TestQuantizeImageFloydSteinbergDiffusesError checks that Floyd-Steinberg
+// dithering, unlike DitherNone, carries quantization error forward: a
+// uniform mid-gray image against a black/white palette should produce a
+// mix of both colors (rather than collapsing to a single color), because
+// each misquantization pushes its error onto later pixels until they tip
+// the other way.
+func TestQuantizeImageFloydSteinbergDiffusesError(t *testing.T) {
+	pal := New([]Color{{0, 0, 0}, {255, 255, 255}})
+	img := solidGray(16, 16, 128)
+
+	out := QuantizeImage(img, pal, DitherFloydSteinberg)
+
+	black := countColor(out, Color{0, 0, 0})
+	white := countColor(out, Color{255, 255, 255})
+	total := 16 * 16
+	if black+white != total {
+		t.Fatalf("expected only black/white pixels, got black=%d white=%d total=%d", black, white, total)
+	}
+	if black == 0 || white == 0 {
+		t.Fatalf("expected a mix of black and white from dithering a mid-gray image, got black=%d white=%d", black, white)
+	}
+
+	// A flat mid-gray input should dither to roughly even black/white, not
+	// collapse lopsided the way DitherNone does.
+	ratio := float64(black) / float64(total)
+	if ratio < 0.35 || ratio > 0.65 {
+		t.Fatalf("expected dithered black ratio near 0.5 for mid-gray input, got %.2f (black=%d white=%d)", ratio, black, white)
+	}
+}
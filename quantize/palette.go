@@ -0,0 +1,251 @@
+package quantize
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// mapBaseColors holds Minecraft's map-color base RGB triples. In-game,
+// each base color is rendered at one of four brightness multipliers
+// (see shadeMultipliers) depending on height, giving the full palette.
+var mapBaseColors = []Color{
+	{127, 178, 56},  // grass
+	{247, 233, 163}, // sand
+	{199, 199, 199}, // cobweb / light gray
+	{255, 0, 0},     // fire / red
+	{160, 160, 255}, // ice
+	{167, 167, 167}, // metal / gray
+	{0, 124, 0},     // foliage / dark green
+	{255, 255, 255}, // snow / white
+	{164, 168, 184}, // clay
+	{151, 109, 77},  // dirt / brown
+	{112, 112, 112}, // stone
+	{64, 64, 255},   // water
+	{143, 119, 72},  // wood
+	{255, 252, 245}, // quartz
+	{216, 127, 51},  // orange
+	{178, 76, 216},  // magenta
+	{102, 153, 216}, // light blue
+	{229, 229, 51},  // yellow
+	{127, 204, 25},  // lime
+	{242, 127, 165}, // pink
+	{76, 76, 76},    // dark gray
+	{153, 153, 153}, // light gray
+	{76, 127, 153},  // cyan
+	{127, 63, 178},  // purple
+	{51, 76, 178},   // blue
+	{102, 76, 51},   // brown
+	{102, 127, 51},  // green
+	{153, 51, 51},   // red
+	{25, 25, 25},    // black
+}
+
+// shadeMultipliers are the brightness factors Minecraft applies to each
+// base color to produce its four on-screen shades (low/normal-low/high/low2).
+var shadeMultipliers = [4]float64{0.71, 0.86, 1.0, 0.53}
+
+// Palette is a fixed set of colors, cached in Oklab space for fast
+// perceptual nearest-neighbour lookup.
+type Palette struct {
+	colors []Color
+	oklab  [][3]float64
+	root   *kdNode
+}
+
+// New builds a Palette from an explicit color list.
+func New(colors []Color) *Palette {
+	p := &Palette{colors: colors}
+	p.oklab = make([][3]float64, len(colors))
+	points := make([]kdPoint, len(colors))
+	for i, c := range colors {
+		lab := toOklab(c)
+		p.oklab[i] = lab
+		points[i] = kdPoint{coords: lab, index: i}
+	}
+	p.root = buildKDTree(points, 0)
+	return p
+}
+
+// Default returns the built-in Minecraft map-color palette, expanded to
+// all four in-game shades of every base color.
+func Default() *Palette {
+	colors := make([]Color, 0, len(mapBaseColors)*len(shadeMultipliers))
+	for _, base := range mapBaseColors {
+		for _, mult := range shadeMultipliers {
+			colors = append(colors, Color{
+				R: scaleChannel(base.R, mult),
+				G: scaleChannel(base.G, mult),
+				B: scaleChannel(base.B, mult),
+			})
+		}
+	}
+	return New(colors)
+}
+
+func scaleChannel(v uint8, mult float64) uint8 {
+	scaled := float64(v) * mult
+	if scaled > 255 {
+		scaled = 255
+	}
+	return uint8(scaled)
+}
+
+// Load reads a palette from a .hex (one "RRGGBB" per line) or .gpl
+// (GIMP palette) file, selected by file extension.
+func Load(path string) (*Palette, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening palette %q: %w", path, err)
+	}
+	defer f.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".gpl":
+		return parseGPL(f)
+	default:
+		return parseHex(f)
+	}
+}
+
+func parseHex(f *os.File) (*Palette, error) {
+	var colors []Color
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "#")
+		if len(line) != 6 {
+			return nil, fmt.Errorf("invalid hex color %q", line)
+		}
+		v, err := strconv.ParseUint(line, 16, 32)
+		if err != nil {
+			return nil, fmt.Errorf("parsing hex color %q: %w", line, err)
+		}
+		colors = append(colors, Color{
+			R: uint8(v >> 16),
+			G: uint8(v >> 8),
+			B: uint8(v),
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(colors) == 0 {
+		return nil, fmt.Errorf("palette file contains no colors")
+	}
+	return New(colors), nil
+}
+
+func parseGPL(f *os.File) (*Palette, error) {
+	var colors []Color
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "GIMP") || strings.HasPrefix(line, "Name:") || strings.HasPrefix(line, "Columns:") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+		r, err1 := strconv.Atoi(fields[0])
+		g, err2 := strconv.Atoi(fields[1])
+		b, err3 := strconv.Atoi(fields[2])
+		if err1 != nil || err2 != nil || err3 != nil {
+			continue
+		}
+		colors = append(colors, Color{R: uint8(r), G: uint8(g), B: uint8(b)})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(colors) == 0 {
+		return nil, fmt.Errorf("palette file contains no colors")
+	}
+	return New(colors), nil
+}
+
+// Nearest returns the palette color perceptually closest to c, searching
+// in Oklab space via a k-d tree.
+func (p *Palette) Nearest(c Color) Color {
+	target := toOklab(c)
+	best := p.root.nearest(target)
+	return p.colors[best]
+}
+
+// kdPoint is a single entry in the k-d tree: an Oklab coordinate and the
+// index of its source color in Palette.colors.
+type kdPoint struct {
+	coords [3]float64
+	index  int
+}
+
+// kdNode is a node in a 3-dimensional k-d tree over Oklab coordinates.
+type kdNode struct {
+	point       kdPoint
+	axis        int
+	left, right *kdNode
+}
+
+func buildKDTree(points []kdPoint, depth int) *kdNode {
+	if len(points) == 0 {
+		return nil
+	}
+	axis := depth % 3
+	sortByAxis(points, axis)
+	mid := len(points) / 2
+	return &kdNode{
+		point: points[mid],
+		axis:  axis,
+		left:  buildKDTree(points[:mid], depth+1),
+		right: buildKDTree(points[mid+1:], depth+1),
+	}
+}
+
+func sortByAxis(points []kdPoint, axis int) {
+	// Insertion sort is fine: palettes are small (typically well under 1k entries).
+	for i := 1; i < len(points); i++ {
+		for j := i; j > 0 && points[j].coords[axis] < points[j-1].coords[axis]; j-- {
+			points[j], points[j-1] = points[j-1], points[j]
+		}
+	}
+}
+
+func (n *kdNode) nearest(target [3]float64) int {
+	best := n.point.index
+	bestDist := sqDist(n.point.coords, target)
+	n.search(target, &best, &bestDist)
+	return best
+}
+
+func (n *kdNode) search(target [3]float64, best *int, bestDist *float64) {
+	if n == nil {
+		return
+	}
+	d := sqDist(n.point.coords, target)
+	if d < *bestDist {
+		*bestDist = d
+		*best = n.point.index
+	}
+
+	diff := target[n.axis] - n.point.coords[n.axis]
+	near, far := n.left, n.right
+	if diff > 0 {
+		near, far = n.right, n.left
+	}
+	near.search(target, best, bestDist)
+	if diff*diff < *bestDist {
+		far.search(target, best, bestDist)
+	}
+}
+
+func sqDist(a, b [3]float64) float64 {
+	dx, dy, dz := a[0]-b[0], a[1]-b[1], a[2]-b[2]
+	return dx*dx + dy*dy + dz*dz
+}
@@ -0,0 +1,38 @@
+// Package stitch composites a directory of overlapping/tiled source
+// images into a single virtual canvas, driven by a JSON manifest of
+// per-image (x, y) offsets.
+package stitch
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Offset locates one source image within the virtual canvas.
+type Offset struct {
+	Path string `json:"path"`
+	X    int    `json:"x"`
+	Y    int    `json:"y"`
+}
+
+// Manifest lists every source image and its offset.
+type Manifest struct {
+	Images []Offset `json:"images"`
+}
+
+// LoadManifest reads a JSON manifest from path.
+func LoadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading manifest %q: %w", path, err)
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("parsing manifest %q: %w", path, err)
+	}
+	if len(m.Images) == 0 {
+		return nil, fmt.Errorf("manifest %q lists no images", path)
+	}
+	return &m, nil
+}
@@ -0,0 +1,185 @@
+package stitch
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// BlendMode selects how overlapping source images are combined.
+type BlendMode int
+
+const (
+	// BlendOverwrite keeps the last manifest entry covering a pixel.
+	BlendOverwrite BlendMode = iota
+	// BlendAverage averages each channel across all contributing images.
+	BlendAverage
+	// BlendMedian takes the per-channel median across all contributing images.
+	BlendMedian
+)
+
+// ParseBlendMode parses the -blend flag value.
+func ParseBlendMode(s string) (BlendMode, bool) {
+	switch s {
+	case "overwrite", "":
+		return BlendOverwrite, true
+	case "average":
+		return BlendAverage, true
+	case "median":
+		return BlendMedian, true
+	default:
+		return BlendOverwrite, false
+	}
+}
+
+// source is one manifest entry: where it sits in the virtual canvas, and
+// its decoded image, loaded lazily and cached on first access.
+type source struct {
+	rect image.Rectangle
+
+	path string
+	once sync.Once
+	img  image.Image
+	err  error
+}
+
+func (s *source) load(dir string) (image.Image, error) {
+	s.once.Do(func() {
+		f, err := os.Open(filepath.Join(dir, s.path))
+		if err != nil {
+			s.err = fmt.Errorf("opening %q: %w", s.path, err)
+			return
+		}
+		defer f.Close()
+
+		img, _, err := image.Decode(f)
+		if err != nil {
+			s.err = fmt.Errorf("decoding %q: %w", s.path, err)
+			return
+		}
+		s.img = img
+	})
+	return s.img, s.err
+}
+
+// StitchedImage composites a manifest of source images into one virtual
+// canvas. It implements image.Image but never materializes the full
+// composite: each source is decoded lazily, on first pixel access, so
+// gigapixel inputs don't need to fit in memory all at once.
+type StitchedImage struct {
+	dir     string
+	sources []*source
+	bounds  image.Rectangle
+	blend   BlendMode
+}
+
+// New builds a StitchedImage from a manifest. Source image dimensions
+// are read via image.DecodeConfig (header only), so the canvas bounds
+// are known without decoding any pixel data.
+func New(dir string, manifest *Manifest, blend BlendMode) (*StitchedImage, error) {
+	sources := make([]*source, 0, len(manifest.Images))
+	var bounds image.Rectangle
+	for i, off := range manifest.Images {
+		w, h, err := decodeConfig(filepath.Join(dir, off.Path))
+		if err != nil {
+			return nil, fmt.Errorf("reading %q: %w", off.Path, err)
+		}
+		rect := image.Rect(off.X, off.Y, off.X+w, off.Y+h)
+		sources = append(sources, &source{path: off.Path, rect: rect})
+		if i == 0 {
+			bounds = rect
+		} else {
+			bounds = bounds.Union(rect)
+		}
+	}
+	return &StitchedImage{dir: dir, sources: sources, bounds: bounds, blend: blend}, nil
+}
+
+func decodeConfig(path string) (w, h int, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	cfg, _, err := image.DecodeConfig(f)
+	if err != nil {
+		return 0, 0, err
+	}
+	return cfg.Width, cfg.Height, nil
+}
+
+// ColorModel implements image.Image.
+func (s *StitchedImage) ColorModel() color.Model { return color.RGBAModel }
+
+// Bounds implements image.Image.
+func (s *StitchedImage) Bounds() image.Rectangle { return s.bounds }
+
+// At implements image.Image, blending every source that covers (x, y)
+// according to s.blend.
+func (s *StitchedImage) At(x, y int) color.Color {
+	pt := image.Pt(x, y)
+	var overwrite color.Color
+	var rs, gs, bs []uint8
+
+	for _, src := range s.sources {
+		if !pt.In(src.rect) {
+			continue
+		}
+		img, err := src.load(s.dir)
+		if err != nil {
+			continue
+		}
+		lx := img.Bounds().Min.X + (x - src.rect.Min.X)
+		ly := img.Bounds().Min.Y + (y - src.rect.Min.Y)
+		r8, g8, b8, _ := img.At(lx, ly).RGBA()
+		r, g, b := uint8(r8>>8), uint8(g8>>8), uint8(b8>>8)
+
+		switch s.blend {
+		case BlendOverwrite:
+			overwrite = color.RGBA{R: r, G: g, B: b, A: 0xFF}
+		default:
+			rs = append(rs, r)
+			gs = append(gs, g)
+			bs = append(bs, b)
+		}
+	}
+
+	switch s.blend {
+	case BlendOverwrite:
+		if overwrite == nil {
+			return color.RGBA{}
+		}
+		return overwrite
+	case BlendMedian:
+		if len(rs) == 0 {
+			return color.RGBA{}
+		}
+		return color.RGBA{R: median(rs), G: median(gs), B: median(bs), A: 0xFF}
+	default: // BlendAverage
+		if len(rs) == 0 {
+			return color.RGBA{}
+		}
+		return color.RGBA{R: average(rs), G: average(gs), B: average(bs), A: 0xFF}
+	}
+}
+
+func average(vals []uint8) uint8 {
+	var sum int
+	for _, v := range vals {
+		sum += int(v)
+	}
+	return uint8(sum / len(vals))
+}
+
+func median(vals []uint8) uint8 {
+	sorted := append([]uint8(nil), vals...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[len(sorted)/2]
+}